@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Valid values for ClientOpt.cookies / Scenario transport cookie mode.
+const (
+	cookiesOff     = "off"
+	cookiesShared  = "shared"
+	cookiesPerUser = "per-user"
+)
+
+// cookieEntry is one stored cookie. domain/path/secure/expires mirror the
+// attributes net/http/cookiejar tracks; fasthttp has no equivalent type.
+type cookieEntry struct {
+	name, value string
+	domain      string
+	// hostOnly is true when the server did not send a Domain attribute, in
+	// which case RFC 6265 restricts the cookie to that exact host rather
+	// than the host and its subdomains.
+	hostOnly bool
+	path     string
+	expires  time.Time
+	secure   bool
+}
+
+// cookieJar is a minimal concurrency-safe, public-suffix-aware cookie jar
+// modeled on net/http/cookiejar. Depending on ClientOpt.cookies it is either
+// shared across all virtual users or built fresh per virtual user so each
+// one keeps its own session (see Requester.Run).
+type cookieJar struct {
+	mu      sync.Mutex
+	byEtld1 map[string][]*cookieEntry
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{byEtld1: make(map[string][]*cookieEntry)}
+}
+
+func etld1(host string) string {
+	host = strings.ToLower(host)
+	if i := strings.IndexByte(host, ':'); i >= 0 {
+		host = host[:i]
+	}
+	e, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return e
+}
+
+// cookiesFor returns the still-valid cookies that match u, dropping expired
+// ones from the jar as it goes.
+func (j *cookieJar) cookiesFor(u *url.URL) []*cookieEntry {
+	key := etld1(u.Hostname())
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := j.byEtld1[key]
+	kept := entries[:0]
+	var matched []*cookieEntry
+	for _, c := range entries {
+		if !c.expires.IsZero() && now.After(c.expires) {
+			continue
+		}
+		kept = append(kept, c)
+		if !domainMatch(u.Hostname(), c.domain, c.hostOnly) {
+			continue
+		}
+		if !pathMatch(u.Path, c.path) {
+			continue
+		}
+		if c.secure && u.Scheme != "https" {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	j.byEtld1[key] = kept
+	return matched
+}
+
+// domainMatch implements RFC 6265 domain-match: a host-only cookie (no
+// Domain attribute on the Set-Cookie) must match host exactly; a domain
+// cookie matches host itself or any subdomain of cookieDomain, with a "."
+// label boundary so a cookie for "example.com" is not sent to
+// "evilexample.com".
+func domainMatch(host, cookieDomain string, hostOnly bool) bool {
+	host = strings.ToLower(host)
+	if hostOnly {
+		return host == cookieDomain
+	}
+	if host == cookieDomain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+cookieDomain)
+}
+
+// pathMatch implements RFC 6265 path-match: cookiePath must be either equal
+// to requestPath or a segment-boundary prefix of it, so a cookie pathed
+// "/foo" is not sent to "/foobar".
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	if strings.HasSuffix(cookiePath, "/") {
+		return true
+	}
+	return requestPath[len(cookiePath)] == '/'
+}
+
+// setCookies parses the Set-Cookie headers on resp and stores them, keyed by
+// eTLD+1 so cookies scoped to sibling subdomains are still found.
+func (j *cookieJar) setCookies(u *url.URL, resp *fasthttp.Response) {
+	key := etld1(u.Hostname())
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := j.byEtld1[key]
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		var c fasthttp.Cookie
+		if err := c.ParseBytes(value); err != nil {
+			return
+		}
+		entry := &cookieEntry{
+			name:   string(c.Key()),
+			value:  string(c.Value()),
+			path:   string(c.Path()),
+			domain: strings.ToLower(string(c.Domain())),
+			secure: c.Secure(),
+		}
+		if entry.path == "" {
+			entry.path = "/"
+		}
+		if entry.domain == "" {
+			entry.hostOnly = true
+			entry.domain = strings.ToLower(u.Hostname())
+		}
+		// Max-Age takes priority over Expires per RFC 6265 when both are
+		// sent; a negative Max-Age means the cookie is already expired.
+		// fasthttp.Cookie reports an absent Max-Age as 0, so a Max-Age of 0
+		// is treated as absent: the cookie falls back to Expires rather than
+		// expiring at once.
+		if maxAge := c.MaxAge(); maxAge != 0 {
+			if maxAge < 0 {
+				entry.expires = time.Now().Add(-time.Second)
+			} else {
+				entry.expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+			}
+		} else if exp := c.Expire(); !exp.IsZero() && exp != fasthttp.CookieExpireUnlimited {
+			entry.expires = exp
+		}
+
+		for i, e := range entries {
+			if e.name == entry.name && e.domain == entry.domain && e.path == entry.path {
+				entries[i] = entry
+				j.byEtld1[key] = entries
+				return
+			}
+		}
+		entries = append(entries, entry)
+		j.byEtld1[key] = entries
+	})
+}
+
+// inject clears any cookies already on req and adds the ones in the jar
+// that currently match u.
+func (j *cookieJar) inject(u *url.URL, req *fasthttp.Request) {
+	req.Header.DelAllCookies()
+	for _, c := range j.cookiesFor(u) {
+		req.Header.SetCookie(c.name, c.value)
+	}
+}
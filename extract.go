@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ExtractRule captures one variable out of a step's response body. Exactly
+// one of JSONPath or Regexp should be set.
+type ExtractRule struct {
+	// JSONPath is a dot/bracket path like "data.token" or "items[0].id"
+	// evaluated against the response body decoded as JSON.
+	JSONPath string `yaml:"jsonPath" json:"jsonPath"`
+	// Regexp is matched against the raw response body; the first capture
+	// group (or the whole match if there is none) becomes the value.
+	Regexp string `yaml:"regexp" json:"regexp"`
+
+	// compiled is Regexp compiled once by compileExtractRules at scenario
+	// load time, so extractVars never re-compiles it on the per-vuser hot
+	// path.
+	compiled *regexp.Regexp
+}
+
+// compileExtractRules compiles every regexp extraction rule in step.Extract
+// once, at scenario load time, and stores the result back into the rule so
+// extractVars can reuse it on every iteration instead of recompiling per
+// request.
+func compileExtractRules(step *ScenarioStep) error {
+	for name, rule := range step.Extract {
+		if rule.Regexp == "" {
+			continue
+		}
+		re, err := regexp.Compile(rule.Regexp)
+		if err != nil {
+			return fmt.Errorf("extract %s: %w", name, err)
+		}
+		rule.compiled = re
+		step.Extract[name] = rule
+	}
+	return nil
+}
+
+// extractVars runs every extraction rule in step.Extract against resp and
+// returns the resulting name->value pairs.
+func extractVars(step *ScenarioStep, resp *fasthttp.Response) (map[string]string, error) {
+	if len(step.Extract) == 0 {
+		return nil, nil
+	}
+	body := resp.Body()
+	vars := make(map[string]string, len(step.Extract))
+
+	var decoded interface{}
+	var decodeErr error
+	decodeOnce := func() (interface{}, error) {
+		if decoded == nil && decodeErr == nil {
+			decodeErr = json.Unmarshal(body, &decoded)
+		}
+		return decoded, decodeErr
+	}
+
+	for name, rule := range step.Extract {
+		switch {
+		case rule.JSONPath != "":
+			v, err := decodeOnce()
+			if err != nil {
+				return vars, fmt.Errorf("extract %s: decode json: %w", name, err)
+			}
+			val, err := jsonPathLookup(v, rule.JSONPath)
+			if err != nil {
+				return vars, fmt.Errorf("extract %s: %w", name, err)
+			}
+			vars[name] = val
+		case rule.Regexp != "":
+			m := rule.compiled.FindSubmatch(body)
+			if m == nil {
+				return vars, fmt.Errorf("extract %s: no match for %q", name, rule.Regexp)
+			}
+			if len(m) > 1 {
+				vars[name] = string(m[1])
+			} else {
+				vars[name] = string(m[0])
+			}
+		default:
+			return vars, fmt.Errorf("extract %s: neither jsonPath nor regexp set", name)
+		}
+	}
+	return vars, nil
+}
+
+// jsonPathLookup resolves a small subset of JSONPath: dot-separated field
+// names with optional "[index]" array accessors, e.g. "data.items[0].id".
+func jsonPathLookup(v interface{}, path string) (string, error) {
+	cur := v
+	for _, part := range strings.Split(path, ".") {
+		field, indices, err := splitIndices(part)
+		if err != nil {
+			return "", err
+		}
+		if field != "" {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("path %q: %q is not an object", path, field)
+			}
+			next, ok := m[field]
+			if !ok {
+				return "", fmt.Errorf("path %q: field %q not found", path, field)
+			}
+			cur = next
+		}
+		for _, idx := range indices {
+			arr, ok := cur.([]interface{})
+			if !ok || idx < 0 || idx >= len(arr) {
+				return "", fmt.Errorf("path %q: index %d out of range", path, idx)
+			}
+			cur = arr[idx]
+		}
+	}
+	return stringify(cur), nil
+}
+
+func splitIndices(part string) (field string, indices []int, err error) {
+	for {
+		open := strings.IndexByte(part, '[')
+		if open < 0 {
+			field += part
+			return field, indices, nil
+		}
+		close := strings.IndexByte(part[open:], ']')
+		if close < 0 {
+			return "", nil, fmt.Errorf("malformed path segment %q", part)
+		}
+		close += open
+		field += part[:open]
+		idx, err := strconv.Atoi(part[open+1 : close])
+		if err != nil {
+			return "", nil, fmt.Errorf("malformed index in %q: %w", part, err)
+		}
+		indices = append(indices, idx)
+		part = part[close+1:]
+		if part == "" {
+			return field, indices, nil
+		}
+	}
+}
+
+func stringify(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	url2 "net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+)
+
+// http2Client adapts golang.org/x/net/http2 so Requester can drive it the
+// same way it drives a *fasthttp.HostClient (see the doer interface in
+// requester.go). fasthttp has no HTTP/2 support of its own, so requests and
+// responses are translated to and from net/http types at the boundary.
+type http2Client struct {
+	client *http.Client
+	scheme string
+	host   string
+
+	streamsOpened  int64
+	goAwayCount    int64
+	rstStreamCount int64
+}
+
+// consumeCounters atomically reads and resets the cumulative stream
+// counters, returning how much each grew since the previous call. Requester
+// calls this once per request (see runSingleIteration) so ReportRecord
+// carries a per-request delta instead of a monotonically-growing total.
+func (c *http2Client) consumeCounters() (streamsOpened, goAwayCount, rstStreamCount int64) {
+	return atomic.SwapInt64(&c.streamsOpened, 0),
+		atomic.SwapInt64(&c.goAwayCount, 0),
+		atomic.SwapInt64(&c.rstStreamCount, 0)
+}
+
+// buildHTTP2Client builds a doer backed by golang.org/x/net/http2 instead of
+// fasthttp. For opt.h2c it dials in cleartext using prior-knowledge (no
+// HTTP/1.1 Upgrade dance), which is what lets us drive HTTP/2-only servers
+// that never negotiate via ALPN.
+func buildHTTP2Client(opt *ClientOpt, r *int64, w *int64) (*http2Client, *fasthttp.RequestHeader, error) {
+	u, err := url2.Parse(opt.url)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr := addMissingPort(u.Host, u.Scheme == "https")
+
+	requestHeader, err := buildRequestHeader(opt, u)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := &http2Client{scheme: u.Scheme, host: u.Host}
+
+	dialer := &net.Dialer{Timeout: opt.dialTimeout}
+	dial := func(network, _ string) (net.Conn, error) {
+		conn, err := dialer.Dial(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return NewMyConn(&writeTimeoutConn{Conn: conn, timeout: opt.writeTimeout}, r, w)
+	}
+
+	transport := &http2.Transport{
+		ReadIdleTimeout: opt.readTimeout,
+		CountError: func(errType string) {
+			switch {
+			case strings.HasPrefix(errType, "recv_goaway_"):
+				atomic.AddInt64(&c.goAwayCount, 1)
+			case strings.HasPrefix(errType, "recv_rststream_"):
+				atomic.AddInt64(&c.rstStreamCount, 1)
+			}
+		},
+	}
+
+	if opt.h2c {
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, _ string, _ *tls.Config) (net.Conn, error) {
+			return dial(network, addr)
+		}
+	} else {
+		tlsConfig, err := buildTLSConfig(opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConfig.NextProtos = []string{"h2"}
+		transport.TLSClientConfig = tlsConfig
+		transport.DialTLS = func(network, _ string, cfg *tls.Config) (net.Conn, error) {
+			rawConn, err := dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(rawConn, cfg)
+			if err := tlsConn.Handshake(); err != nil {
+				return nil, err
+			}
+			return tlsConn, nil
+		}
+	}
+
+	c.client = &http.Client{Transport: transport}
+	return c, requestHeader, nil
+}
+
+// writeTimeoutConn applies opt.writeTimeout to every Write via the
+// connection's write deadline; net/http2 has no WriteTimeout knob of its
+// own (ReadIdleTimeout only covers reads), so we enforce it at the net.Conn
+// level instead.
+type writeTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *writeTimeoutConn) Write(b []byte) (int, error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *http2Client) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return c.do(req, resp, 0)
+}
+
+func (c *http2Client) DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	return c.do(req, resp, timeout)
+}
+
+func (c *http2Client) do(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error {
+	httpReq, err := c.toHTTPRequest(req)
+	if err != nil {
+		return err
+	}
+
+	client := c.client
+	if timeout > 0 {
+		cl := *c.client
+		cl.Timeout = timeout
+		client = &cl
+	}
+
+	atomic.AddInt64(&c.streamsOpened, 1)
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+
+	resp.SetStatusCode(httpResp.StatusCode)
+	resp.SetBody(body)
+	for k, vv := range httpResp.Header {
+		for _, v := range vv {
+			resp.Header.Add(k, v)
+		}
+	}
+	return nil
+}
+
+func (c *http2Client) toHTTPRequest(req *fasthttp.Request) (*http.Request, error) {
+	u := &url2.URL{
+		Scheme:   c.scheme,
+		Host:     c.host,
+		Path:     string(req.URI().Path()),
+		RawQuery: string(req.URI().QueryString()),
+	}
+	httpReq, err := http.NewRequest(string(req.Header.Method()), u.String(), bytes.NewReader(req.Body()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		if strings.EqualFold(string(key), "Host") {
+			return
+		}
+		httpReq.Header.Add(string(key), string(value))
+	})
+	httpReq.Host = string(req.Header.Host())
+	return httpReq, nil
+}
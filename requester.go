@@ -35,6 +35,27 @@ type ReportRecord struct {
 	readBytes        int64
 	writeBytes       int64
 	concurrencyCount int
+
+	// stepName is set when the requester is driving a Scenario (see
+	// scenario.go) so the reporter can break latency/error rate down per
+	// step; it is empty in single-URL mode.
+	stepName string
+
+	// targetID is set when the requester is driving a weighted multi-target
+	// URL set (see targets.go) so the reporter can break latency/RPS/error
+	// down per target; it is unused (0) in single-URL and scenario mode.
+	targetID int
+
+	// http2StreamsOpened, http2GoAwayCount and http2RstStreamCount are only
+	// populated when the requester is driving HTTP/2 (see ClientOpt.http2).
+	// Each is the delta observed since the previous ReportRecord, not a
+	// running total, so the reporter can sum them across records to get the
+	// run's totals; see http2Client.consumeCounters. Only the single-URL path
+	// (runSingleIteration) fills them in: scenario and multi-target runs
+	// never use the HTTP/2 transport.
+	http2StreamsOpened  int64
+	http2GoAwayCount    int64
+	http2RstStreamCount int64
 }
 
 var recordPool = sync.Pool{
@@ -92,6 +113,14 @@ func ThroughputInterceptorDial(dial fasthttp.DialFunc, r *int64, w *int64) fasth
 	}
 }
 
+// doer is the subset of *fasthttp.HostClient that Requester drives. It lets
+// the HTTP/2 transport (see http2.go) stand in for the default fasthttp
+// transport without Requester caring which one it got.
+type doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
+}
+
 type Requester struct {
 	concurrency int
 	reqRate     *rate.Limit
@@ -99,10 +128,38 @@ type Requester struct {
 	duration    time.Duration
 	rampUp      int
 	clientOpt   *ClientOpt
-	httpClient  *fasthttp.HostClient
+	httpClient  doer
+	isTLS       bool
+	targetURL   *url2.URL
 	httpHeader  *fasthttp.RequestHeader
 	errWriter   io.Writer
 
+	// scenario is non-nil when the requester is driving a multi-step
+	// Scenario instead of hammering a single URL; see scenario.go.
+	scenario *Scenario
+
+	// sharedCookieJar is only set when clientOpt.cookies == "shared"; in
+	// "per-user" mode each virtual user builds its own jar instead (see
+	// Run), and in "off" mode cookieJarFor returns nil.
+	sharedCookieJar *cookieJar
+
+	// validators run against each response when clientOpt.stream is set;
+	// see validate.go.
+	validators []Validator
+
+	// multiTargets and targetSampler are only set by NewMultiTargetRequester;
+	// see targets.go.
+	multiTargets  []*multiTarget
+	targetSampler *aliasSampler
+	dataFile      *dataFileIterator
+
+	// sinks mirror every record onto a bounded, drop-oldest side channel
+	// (see publishToSinks) so JSONL/metrics/remote_write consumers never
+	// slow down the hot path; see sinks.go.
+	sinks          []Sink
+	sinkChan       chan *ReportRecord
+	droppedRecords int64
+
 	recordChan chan *ReportRecord
 	closeOnce  sync.Once
 	wg         sync.WaitGroup
@@ -133,6 +190,25 @@ type ClientOpt struct {
 	socks5Proxy string
 	contentType string
 	host        string
+
+	http2 bool
+	h2c   bool
+
+	// cookies is one of "off" (default), "shared" or "per-user"; see
+	// Requester.cookieJarFor.
+	cookies string
+
+	// stream switches response body consumption from BodyWriteTo (which
+	// fasthttp still buffers in full before discarding) to an incremental
+	// reader. It is required by the body validators (--expect-body-regex,
+	// --expect-body-size, --expect-content-type); --expect-status and
+	// --expect-header also run without it.
+	stream            bool
+	expectStatus      string
+	expectHeaders     []string
+	expectBodyRegex   string
+	expectBodySize    string
+	expectContentType string
 }
 
 func NewRequester(concurrency int, requests int64, duration time.Duration, reqRate *rate.Limit, errWriter io.Writer, clientOpt *ClientOpt, rampUp int) (*Requester, error) {
@@ -150,6 +226,30 @@ func NewRequester(concurrency int, requests int64, duration time.Duration, reqRa
 		clientOpt:   clientOpt,
 		recordChan:  make(chan *ReportRecord, maxResult),
 	}
+	u, err := url2.Parse(clientOpt.url)
+	if err != nil {
+		return nil, err
+	}
+	r.isTLS = u.Scheme == "https"
+	r.targetURL = u
+	if clientOpt.cookies == cookiesShared {
+		r.sharedCookieJar = newCookieJar()
+	}
+	if clientOpt.bodyFile != "" {
+		// Read the whole body once at startup instead of re-opening and
+		// streaming it from disk on every request.
+		data, err := os.ReadFile(clientOpt.bodyFile)
+		if err != nil {
+			return nil, err
+		}
+		clientOpt.bodyBytes = data
+	}
+	validators, err := buildValidators(clientOpt)
+	if err != nil {
+		return nil, err
+	}
+	r.validators = validators
+
 	client, header, err := buildRequestClient(clientOpt, &r.readBytes, &r.writeBytes)
 	if err != nil {
 		return nil, err
@@ -159,6 +259,130 @@ func NewRequester(concurrency int, requests int64, duration time.Duration, reqRa
 	return r, nil
 }
 
+// NewScenarioRequester builds a Requester that drives a multi-step Scenario
+// instead of a single URL. transportOpt supplies the connection-level
+// settings (timeouts, TLS, proxy) since a scenario's steps carry their own
+// method/URL/headers/body.
+func NewScenarioRequester(concurrency int, requests int64, duration time.Duration, reqRate *rate.Limit, errWriter io.Writer, scenario *Scenario, transportOpt *ClientOpt, rampUp int) (*Requester, error) {
+	maxResult := concurrency * 100
+	if maxResult > 8192 {
+		maxResult = 8192
+	}
+	r := &Requester{
+		concurrency: concurrency,
+		reqRate:     reqRate,
+		requests:    requests,
+		duration:    duration,
+		rampUp:      rampUp,
+		errWriter:   errWriter,
+		clientOpt:   transportOpt,
+		scenario:    scenario,
+		recordChan:  make(chan *ReportRecord, maxResult),
+	}
+	if transportOpt.cookies == cookiesShared {
+		r.sharedCookieJar = newCookieJar()
+	}
+	validators, err := buildValidators(transportOpt)
+	if err != nil {
+		return nil, err
+	}
+	r.validators = validators
+
+	client, err := buildScenarioClient(transportOpt, &r.readBytes, &r.writeBytes)
+	if err != nil {
+		return nil, err
+	}
+	r.httpClient = client
+	return r, nil
+}
+
+// NewMultiTargetRequester builds a Requester that spreads requests across a
+// weighted set of targets (see targets.go) instead of a single URL.
+// dataFilePath, if non-empty, is round-robined across the worker pool to
+// fill in {id}-style placeholders in target URL paths and queries; target
+// bodies are sent as given, without substitution.
+func NewMultiTargetRequester(concurrency int, requests int64, duration time.Duration, reqRate *rate.Limit, errWriter io.Writer, targets []TargetSpec, dataFilePath string, transportOpt *ClientOpt, rampUp int) (*Requester, error) {
+	maxResult := concurrency * 100
+	if maxResult > 8192 {
+		maxResult = 8192
+	}
+	r := &Requester{
+		concurrency: concurrency,
+		reqRate:     reqRate,
+		requests:    requests,
+		duration:    duration,
+		rampUp:      rampUp,
+		errWriter:   errWriter,
+		clientOpt:   transportOpt,
+		recordChan:  make(chan *ReportRecord, maxResult),
+	}
+	if transportOpt.cookies == cookiesShared {
+		r.sharedCookieJar = newCookieJar()
+	}
+	validators, err := buildValidators(transportOpt)
+	if err != nil {
+		return nil, err
+	}
+	r.validators = validators
+
+	multiTargets, err := buildMultiTargetClients(targets, transportOpt, &r.readBytes, &r.writeBytes)
+	if err != nil {
+		return nil, err
+	}
+	weights := make([]float64, len(multiTargets))
+	for i, t := range multiTargets {
+		weights[i] = t.weight
+	}
+	sampler, err := newAliasSampler(weights)
+	if err != nil {
+		return nil, err
+	}
+	r.multiTargets = multiTargets
+	r.targetSampler = sampler
+
+	if dataFilePath != "" {
+		df, err := loadDataFile(dataFilePath)
+		if err != nil {
+			return nil, err
+		}
+		r.dataFile = df
+	}
+
+	return r, nil
+}
+
+// buildScenarioClient builds a doer that, unlike a *fasthttp.HostClient, is
+// not pinned to a single host: each scenario step can target a different
+// backend, and fasthttp.Client pools connections per host internally.
+func buildScenarioClient(opt *ClientOpt, r *int64, w *int64) (*fasthttp.Client, error) {
+	tlsConfig, err := buildTLSConfig(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var dial fasthttp.DialFunc
+	if opt.socks5Proxy != "" {
+		proxy := opt.socks5Proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "socks5://" + proxy
+		}
+		dial = fasthttpproxy.FasthttpSocksDialer(proxy)
+	} else {
+		dial = fasthttpproxy.FasthttpProxyHTTPDialerTimeout(opt.dialTimeout)
+	}
+
+	return &fasthttp.Client{
+		Name:                          "plow",
+		MaxConnsPerHost:               opt.maxConns,
+		ReadTimeout:                   opt.readTimeout,
+		WriteTimeout:                  opt.writeTimeout,
+		DisableHeaderNamesNormalizing: true,
+		StreamResponseBody:            opt.stream,
+		TLSConfig:                     tlsConfig,
+		Dial:                          ThroughputInterceptorDial(dial, r, w),
+	}, nil
+}
+
 func addMissingPort(addr string, isTLS bool) string {
 	n := strings.Index(addr, ":")
 	if n >= 0 {
@@ -186,36 +410,29 @@ func buildTLSConfig(opt *ClientOpt) (*tls.Config, error) {
 	}, nil
 }
 
-func buildRequestClient(opt *ClientOpt, r *int64, w *int64) (*fasthttp.HostClient, *fasthttp.RequestHeader, error) {
+func buildRequestClient(opt *ClientOpt, r *int64, w *int64) (doer, *fasthttp.RequestHeader, error) {
+	if opt.http2 || opt.h2c {
+		return buildHTTP2Client(opt, r, w)
+	}
+
 	u, err := url2.Parse(opt.url)
 	if err != nil {
 		return nil, nil, err
 	}
-	httpClient := &fasthttp.HostClient{
-		Addr:                          addMissingPort(u.Host, u.Scheme == "https"),
-		IsTLS:                         u.Scheme == "https",
-		Name:                          "plow",
-		MaxConns:                      opt.maxConns,
-		ReadTimeout:                   opt.readTimeout,
-		WriteTimeout:                  opt.writeTimeout,
-		DisableHeaderNamesNormalizing: true,
-	}
-	if opt.socks5Proxy != "" {
-		if !strings.Contains(opt.socks5Proxy, "://") {
-			opt.socks5Proxy = "socks5://" + opt.socks5Proxy
-		}
-		httpClient.Dial = fasthttpproxy.FasthttpSocksDialer(opt.socks5Proxy)
-	} else {
-		httpClient.Dial = fasthttpproxy.FasthttpProxyHTTPDialerTimeout(opt.dialTimeout)
+	httpClient, err := buildHostClient(addMissingPort(u.Host, u.Scheme == "https"), u.Scheme == "https", opt, r, w)
+	if err != nil {
+		return nil, nil, err
 	}
-	httpClient.Dial = ThroughputInterceptorDial(httpClient.Dial, r, w)
 
-	tlsConfig, err := buildTLSConfig(opt)
+	requestHeader, err := buildRequestHeader(opt, u)
 	if err != nil {
 		return nil, nil, err
 	}
-	httpClient.TLSConfig = tlsConfig
 
+	return httpClient, requestHeader, nil
+}
+
+func buildRequestHeader(opt *ClientOpt, u *url2.URL) (*fasthttp.RequestHeader, error) {
 	var requestHeader fasthttp.RequestHeader
 	if opt.contentType != "" {
 		requestHeader.SetContentType(opt.contentType)
@@ -230,12 +447,26 @@ func buildRequestClient(opt *ClientOpt, r *int64, w *int64) (*fasthttp.HostClien
 	for _, h := range opt.headers {
 		n := strings.SplitN(h, ":", 2)
 		if len(n) != 2 {
-			return nil, nil, fmt.Errorf("invalid header: %s", h)
+			return nil, fmt.Errorf("invalid header: %s", h)
 		}
 		requestHeader.Set(n[0], n[1])
 	}
+	return &requestHeader, nil
+}
 
-	return httpClient, &requestHeader, nil
+// cookieJarFor returns the jar a virtual user should use, or nil when
+// cookies are disabled. "shared" hands back the one jar built in
+// NewRequester/NewScenarioRequester; "per-user" builds a fresh jar per
+// virtual user so sessions stay distinct.
+func (r *Requester) cookieJarFor() *cookieJar {
+	switch r.clientOpt.cookies {
+	case cookiesShared:
+		return r.sharedCookieJar
+	case cookiesPerUser:
+		return newCookieJar()
+	default:
+		return nil
+	}
 }
 
 func (r *Requester) Cancel() {
@@ -246,20 +477,100 @@ func (r *Requester) RecordChan() <-chan *ReportRecord {
 	return r.recordChan
 }
 
+// DroppedRecords reports how many records were discarded from the sink
+// fan-out channel because a sink fell behind; see publishToSinks.
+func (r *Requester) DroppedRecords() int64 {
+	return atomic.LoadInt64(&r.droppedRecords)
+}
+
 func (r *Requester) closeRecord() {
 	r.closeOnce.Do(func() {
 		close(r.recordChan)
+		if r.sinkChan != nil {
+			close(r.sinkChan)
+		}
 	})
 }
 
+// SetSinks wires up raw-record sinks (JSONL/metrics/remote_write; see
+// sinks.go). It must be called before Run. Records reach sinks over a
+// bounded, drop-oldest channel so a slow or stuck sink can never back up
+// into the request hot path; drops are counted in droppedRecords.
+func (r *Requester) SetSinks(sinks []Sink) {
+	r.sinks = sinks
+	if len(sinks) == 0 {
+		return
+	}
+	r.sinkChan = make(chan *ReportRecord, 4096)
+	go r.runSinkFanout()
+}
+
+func (r *Requester) runSinkFanout() {
+	for rr := range r.sinkChan {
+		for _, s := range r.sinks {
+			s.OnRecord(rr)
+		}
+	}
+	for _, s := range r.sinks {
+		_ = s.Flush()
+	}
+}
+
+// publishToSinks mirrors rr onto sinkChan without ever blocking the caller:
+// if the channel is full, the oldest pending record is dropped to make
+// room, and droppedRecords counts how many records never reached a sink.
+//
+// rr itself is owned by recordChan's consumer, which recycles it back into
+// recordPool once read; sinks run on a separate goroutine (see
+// runSinkFanout) that can still be reading it well after that happens. So a
+// copy, not rr, is what actually goes on sinkChan.
+func (r *Requester) publishToSinks(rr *ReportRecord) {
+	if r.sinkChan == nil {
+		return
+	}
+	cp := new(ReportRecord)
+	*cp = *rr
+	select {
+	case r.sinkChan <- cp:
+		return
+	default:
+	}
+	select {
+	case <-r.sinkChan:
+		atomic.AddInt64(&r.droppedRecords, 1)
+	default:
+	}
+	select {
+	case r.sinkChan <- cp:
+	default:
+		atomic.AddInt64(&r.droppedRecords, 1)
+	}
+}
+
 func (r *Requester) DoRequest(req *fasthttp.Request, resp *fasthttp.Response, rr *ReportRecord) {
+	r.doRequestWith(r.httpClient, req, resp, rr)
+}
+
+// doRequestWith is DoRequest against an explicit client rather than
+// r.httpClient, so the multi-target loop can drive whichever per-target
+// client a given iteration picked (see runMultiTargetVUser).
+func (r *Requester) doRequestWith(client doer, req *fasthttp.Request, resp *fasthttp.Response, rr *ReportRecord) {
+	for _, s := range r.sinks {
+		s.OnRequestStart()
+	}
+	defer func() {
+		for _, s := range r.sinks {
+			s.OnRequestComplete()
+		}
+	}()
+
 	startTime := time.Unix(0, atomic.LoadInt64(&startTimeUnixNano))
 	t1 := time.Since(startTime)
 	var err error
 	if r.clientOpt.doTimeout > 0 {
-		err = r.httpClient.DoTimeout(req, resp, r.clientOpt.doTimeout)
+		err = client.DoTimeout(req, resp, r.clientOpt.doTimeout)
 	} else {
-		err = r.httpClient.Do(req, resp)
+		err = client.Do(req, resp)
 	}
 
 	if err != nil {
@@ -274,7 +585,21 @@ func (r *Requester) DoRequest(req *fasthttp.Request, resp *fasthttp.Response, rr
 		_, _ = r.errWriter.Write([]byte(fmt.Sprintf("\n%d %s\n", resp.StatusCode(), rr.cost)))
 		_, _ = r.errWriter.Write([]byte(fmt.Sprintf("%s", &resp.Header)))
 	}
-	err = resp.BodyWriteTo(writeTo)
+
+	if len(r.validators) > 0 {
+		if verr := validateHeader(r.validators, resp); verr != nil {
+			_ = drainBodyStream(resp, writeTo, r.clientOpt.stream)
+			rr.cost = time.Since(startTime) - t1
+			rr.error = verr.Error()
+			return
+		}
+	}
+
+	if r.clientOpt.stream {
+		err = streamBody(resp, writeTo, r.validators)
+	} else {
+		err = resp.BodyWriteTo(writeTo)
+	}
 	if err != nil {
 		rr.cost = time.Since(startTime) - t1
 		rr.error = err.Error()
@@ -286,6 +611,203 @@ func (r *Requester) DoRequest(req *fasthttp.Request, resp *fasthttp.Response, rr
 	rr.error = ""
 }
 
+// runScenarioVUser executes r.scenario's steps, in order, over and over
+// until ctx is cancelled or semaphore is exhausted, carrying variables
+// captured from one step's response into the template substitution of
+// later steps. --rate and -n apply per request, i.e. per step, not per
+// whole scenario pass, so both knobs mean the same thing here as they do
+// against a single URL.
+func (r *Requester) runScenarioVUser(ctx context.Context, limiter *rate.Limiter, semaphore *int64, cancelFunc func(), concurrencyCount int) {
+	vars := make(map[string]string)
+	resp := &fasthttp.Response{}
+	jar := r.cookieJarFor()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		for _, step := range r.scenario.Steps {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+			}
+
+			if r.requests > 0 && atomic.AddInt64(semaphore, -1) < 0 {
+				cancelFunc()
+				return
+			}
+
+			req := fasthttp.AcquireRequest()
+			r.prepareStepRequest(req, step, vars)
+			stepURL, urlErr := url2.Parse(string(req.URI().FullURI()))
+			if jar != nil && urlErr == nil {
+				jar.inject(stepURL, req)
+			}
+
+			resp.Reset()
+			rr := recordPool.Get().(*ReportRecord)
+			rr.stepName = step.Name
+			r.DoRequest(req, resp, rr)
+			if jar != nil && urlErr == nil && rr.error == "" {
+				jar.setCookies(stepURL, resp)
+			}
+
+			var extracted map[string]string
+			if rr.error == "" {
+				var extractErr error
+				extracted, extractErr = extractVars(step, resp)
+				if extractErr != nil {
+					rr.error = fmt.Sprintf("extract: %s", extractErr)
+				}
+			}
+
+			rr.readBytes = atomic.LoadInt64(&r.readBytes)
+			rr.writeBytes = atomic.LoadInt64(&r.writeBytes)
+			rr.concurrencyCount = concurrencyCount
+			r.publishToSinks(rr)
+			r.recordChan <- rr
+
+			for k, v := range extracted {
+				vars[k] = v
+			}
+
+			fasthttp.ReleaseRequest(req)
+
+			if step.thinkTime > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(step.thinkTime):
+				}
+			}
+		}
+	}
+}
+
+// prepareStepRequest renders a scenario step's method/URL/headers/body
+// against vars and fills req accordingly.
+func (r *Requester) prepareStepRequest(req *fasthttp.Request, step *ScenarioStep, vars map[string]string) {
+	req.Header.SetMethod(step.Method)
+	req.SetRequestURI(renderTemplate(step.URL, vars))
+	for k, v := range step.Headers {
+		req.Header.Set(k, renderTemplate(v, vars))
+	}
+	if step.Body != "" {
+		req.SetBodyString(renderTemplate(step.Body, vars))
+	}
+}
+
+// runMultiTargetVUser picks a target per iteration via r.targetSampler's
+// weighted-random sampling and drives it the same way the single-URL loop
+// drives its one cached request, reusing one pre-built *fasthttp.Request per
+// target for the lifetime of the virtual user.
+func (r *Requester) runMultiTargetVUser(ctx context.Context, limiter *rate.Limiter, semaphore *int64, cancelFunc func(), concurrencyCount int) {
+	reqs := make([]*fasthttp.Request, len(r.multiTargets))
+	for i, t := range r.multiTargets {
+		req := fasthttp.AcquireRequest()
+		t.header.CopyTo(&req.Header)
+		if t.isTLS {
+			req.URI().SetScheme("https")
+			req.URI().SetHostBytes(req.Header.Host())
+		}
+		req.SetBodyRaw(t.bodyBytes)
+		reqs[i] = req
+	}
+	defer func() {
+		for _, req := range reqs {
+			fasthttp.ReleaseRequest(req)
+		}
+	}()
+
+	resp := &fasthttp.Response{}
+	jar := r.cookieJarFor()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				continue
+			}
+		}
+
+		if r.requests > 0 && atomic.AddInt64(semaphore, -1) < 0 {
+			cancelFunc()
+			return
+		}
+
+		idx := r.targetSampler.Next()
+		target := r.multiTargets[idx]
+		req := reqs[idx]
+
+		if r.dataFile != nil {
+			id := url2.PathEscape(r.dataFile.Next())
+			req.SetRequestURI(strings.Replace(target.uriTemplate, "{id}", id, -1))
+		}
+
+		var targetURL *url2.URL
+		if jar != nil {
+			if u, err := url2.Parse(string(req.URI().FullURI())); err == nil {
+				targetURL = u
+				jar.inject(targetURL, req)
+			}
+		}
+
+		resp.Reset()
+		rr := recordPool.Get().(*ReportRecord)
+		rr.targetID = idx
+		r.doRequestWith(target.client, req, resp, rr)
+		if jar != nil && targetURL != nil && rr.error == "" {
+			jar.setCookies(targetURL, resp)
+		}
+		rr.readBytes = atomic.LoadInt64(&r.readBytes)
+		rr.writeBytes = atomic.LoadInt64(&r.writeBytes)
+		rr.concurrencyCount = concurrencyCount
+		r.publishToSinks(rr)
+		r.recordChan <- rr
+	}
+}
+
+// runSingleIteration drives one request/response cycle of the single-URL
+// hot loop in Run: inject cookies, issue the request, record byte/HTTP2
+// counters and hand the record off to the reporter and any sinks. It is
+// factored out of Run so the zero-allocation claim on this path can be
+// pinned by a benchmark-style test (see requester_test.go) without having
+// to drive the full goroutine/signal/ramp-up machinery.
+func (r *Requester) runSingleIteration(req *fasthttp.Request, resp *fasthttp.Response, jar *cookieJar, concurrencyCount int) {
+	if jar != nil {
+		jar.inject(r.targetURL, req)
+	}
+	resp.Reset()
+	rr := recordPool.Get().(*ReportRecord)
+	r.DoRequest(req, resp, rr)
+	if jar != nil && rr.error == "" {
+		jar.setCookies(r.targetURL, resp)
+	}
+	rr.readBytes = atomic.LoadInt64(&r.readBytes)
+	rr.writeBytes = atomic.LoadInt64(&r.writeBytes)
+	rr.concurrencyCount = concurrencyCount
+	if h2, ok := r.httpClient.(*http2Client); ok {
+		rr.http2StreamsOpened, rr.http2GoAwayCount, rr.http2RstStreamCount = h2.consumeCounters()
+	}
+	r.publishToSinks(rr)
+	r.recordChan <- rr
+}
+
 func (r *Requester) Run() {
 	// handle ctrl-c
 	sigs := make(chan os.Signal, 1)
@@ -333,13 +855,28 @@ func (r *Requester) Run() {
 						panic(v)
 					}
 				}()
-				req := &fasthttp.Request{}
-				resp := &fasthttp.Response{}
+
+				if r.scenario != nil {
+					r.runScenarioVUser(ctx, limiter, &semaphore, cancelFunc, concurrencyCount)
+					return
+				}
+
+				if r.multiTargets != nil {
+					r.runMultiTargetVUser(ctx, limiter, &semaphore, cancelFunc, concurrencyCount)
+					return
+				}
+
+				req := fasthttp.AcquireRequest()
+				resp := fasthttp.AcquireResponse()
+				defer fasthttp.ReleaseRequest(req)
+				defer fasthttp.ReleaseResponse(resp)
 				r.httpHeader.CopyTo(&req.Header)
-				if r.httpClient.IsTLS {
+				if r.isTLS {
 					req.URI().SetScheme("https")
 					req.URI().SetHostBytes(req.Header.Host())
 				}
+				req.SetBodyRaw(r.clientOpt.bodyBytes)
+				jar := r.cookieJarFor()
 
 				for {
 					select {
@@ -360,29 +897,7 @@ func (r *Requester) Run() {
 						return
 					}
 
-					if r.clientOpt.bodyFile != "" {
-						file, err := os.Open(r.clientOpt.bodyFile)
-						if err != nil {
-							rr := recordPool.Get().(*ReportRecord)
-							rr.cost = 0
-							rr.error = err.Error()
-							rr.readBytes = atomic.LoadInt64(&r.readBytes)
-							rr.writeBytes = atomic.LoadInt64(&r.writeBytes)
-							rr.concurrencyCount = concurrencyCount
-							r.recordChan <- rr
-							continue
-						}
-						req.SetBodyStream(file, -1)
-					} else {
-						req.SetBodyRaw(r.clientOpt.bodyBytes)
-					}
-					resp.Reset()
-					rr := recordPool.Get().(*ReportRecord)
-					r.DoRequest(req, resp, rr)
-					rr.readBytes = atomic.LoadInt64(&r.readBytes)
-					rr.writeBytes = atomic.LoadInt64(&r.writeBytes)
-					rr.concurrencyCount = concurrencyCount
-					r.recordChan <- rr
+					r.runSingleIteration(req, resp, jar, concurrencyCount)
 				}
 			}()
 		}
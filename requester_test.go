@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// fakeDoer is a doer that never touches the network, so
+// TestRunSingleIterationZeroAllocs measures only the bookkeeping Requester
+// itself does around a request/response cycle.
+type fakeDoer struct{}
+
+func (fakeDoer) Do(_ *fasthttp.Request, resp *fasthttp.Response) error {
+	resp.SetStatusCode(fasthttp.StatusOK)
+	return nil
+}
+
+func (fakeDoer) DoTimeout(_ *fasthttp.Request, resp *fasthttp.Response, _ time.Duration) error {
+	resp.SetStatusCode(fasthttp.StatusOK)
+	return nil
+}
+
+// TestRunSingleIterationZeroAllocs pins the zero-allocation hot path
+// required when the request body comes from bytes: a pooled request/response
+// pair reused across iterations, no per-iteration recordPool growth, and no
+// sink fan-out overhead when no sinks are configured. Modeled on fasthttp's
+// own use of testing.AllocsPerRun to catch allocation regressions.
+func TestRunSingleIterationZeroAllocs(t *testing.T) {
+	r := &Requester{
+		clientOpt:  &ClientOpt{bodyBytes: []byte("hello")},
+		httpClient: fakeDoer{},
+		errWriter:  io.Discard,
+		recordChan: make(chan *ReportRecord, 1),
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.Header.SetMethod("GET")
+	req.SetRequestURI("http://example.com/")
+	req.SetBodyRaw(r.clientOpt.bodyBytes)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		r.runSingleIteration(req, resp, nil, 1)
+		// Mimic the reporter recycling the record (see recordPool), which
+		// is what keeps this path allocation-free in steady state.
+		recordPool.Put(<-r.recordChan)
+	})
+	if allocs != 0 {
+		t.Fatalf("runSingleIteration: got %v allocs/op on the bytes-body hot path, want 0", allocs)
+	}
+}
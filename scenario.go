@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Scenario describes an ordered multi-step load test: a virtual user walks
+// through Steps in order on every iteration, reusing variables captured
+// from earlier responses (see extract.go) to fill in later steps.
+type Scenario struct {
+	Steps []*ScenarioStep `yaml:"steps" json:"steps"`
+}
+
+type ScenarioStep struct {
+	Name    string            `yaml:"name" json:"name"`
+	Method  string            `yaml:"method" json:"method"`
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Body    string            `yaml:"body" json:"body"`
+
+	// Extract maps a variable name (usable as ${name} in later steps) to
+	// the rule used to pull it out of this step's response.
+	Extract map[string]ExtractRule `yaml:"extract" json:"extract"`
+
+	// ThinkTime is how long a virtual user waits after this step before
+	// moving on to the next one, written as a Go duration string (e.g.
+	// "500ms", "5s"). Parsed once into thinkTime by LoadScenario.
+	ThinkTime string `yaml:"thinkTime" json:"thinkTime"`
+
+	// thinkTime is ThinkTime parsed at load time; see LoadScenario.
+	thinkTime time.Duration
+}
+
+// LoadScenario reads a scenario file, dispatching on extension to a YAML or
+// JSON decoder.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Scenario
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &s)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &s)
+	default:
+		return nil, fmt.Errorf("unrecognized scenario file extension: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %s has no steps", path)
+	}
+	for i, step := range s.Steps {
+		if step.Method == "" {
+			step.Method = "GET"
+		}
+		if step.URL == "" {
+			return nil, fmt.Errorf("scenario %s: step %d has no url", path, i)
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if step.ThinkTime != "" {
+			d, err := time.ParseDuration(step.ThinkTime)
+			if err != nil {
+				return nil, fmt.Errorf("scenario %s: step %d thinkTime: %w", path, i, err)
+			}
+			step.thinkTime = d
+		}
+		if err := compileExtractRules(step); err != nil {
+			return nil, fmt.Errorf("scenario %s: step %d: %w", path, i, err)
+		}
+	}
+	return &s, nil
+}
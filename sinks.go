@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sink receives a copy of every ReportRecord as Requester produces it,
+// independent of whatever consumes RecordChan(); see Requester.publishToSinks.
+// OnRequestStart/OnRequestComplete bracket the in-flight HTTP call itself,
+// which is otherwise invisible to a sink that only ever sees completed
+// records; most sinks no-op them.
+type Sink interface {
+	OnRequestStart()
+	OnRequestComplete()
+	OnRecord(rr *ReportRecord)
+	Flush() error
+}
+
+// ---- JSONL sink ----
+
+// jsonlSink writes one JSON object per record to a file or stdout.
+type jsonlSink struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+type jsonlRecord struct {
+	TimestampUnixNano int64   `json:"ts"`
+	CostMillis        float64 `json:"cost_ms"`
+	Code              int     `json:"code"`
+	Error             string  `json:"error,omitempty"`
+	ReadBytes         int64   `json:"read_bytes"`
+	WriteBytes        int64   `json:"write_bytes"`
+	Concurrency       int     `json:"concurrency"`
+	Target            int     `json:"target,omitempty"`
+	Step              string  `json:"step,omitempty"`
+}
+
+// newJSONLSink opens path for writing, or writes to stdout for "" or "-".
+func newJSONLSink(path string) (*jsonlSink, error) {
+	var w io.Writer
+	var closer io.Closer
+	if path == "" || path == "-" {
+		w = os.Stdout
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		w, closer = f, f
+	}
+	bw := bufio.NewWriter(w)
+	return &jsonlSink{w: bw, enc: json.NewEncoder(bw), closer: closer}, nil
+}
+
+func (s *jsonlSink) OnRequestStart()    {}
+func (s *jsonlSink) OnRequestComplete() {}
+
+func (s *jsonlSink) OnRecord(rr *ReportRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(jsonlRecord{
+		TimestampUnixNano: time.Now().UnixNano(),
+		CostMillis:        float64(rr.cost) / float64(time.Millisecond),
+		Code:              rr.code,
+		Error:             rr.error,
+		ReadBytes:         rr.readBytes,
+		WriteBytes:        rr.writeBytes,
+		Concurrency:       rr.concurrencyCount,
+		Target:            rr.targetID,
+		Step:              rr.stepName,
+	})
+}
+
+func (s *jsonlSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// ---- OpenMetrics/Prometheus exposition sink ----
+
+// latencyBucketBoundsMs are the histogram bucket upper bounds, in
+// milliseconds, exposed by metricsSink.
+var latencyBucketBoundsMs = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// metricsSink serves a Prometheus text-exposition /metrics endpoint so a
+// scraper can poll in-flight RPS/latency/error counts over the life of a run.
+type metricsSink struct {
+	mu       sync.Mutex
+	count    int64
+	errors   int64
+	sum      float64
+	buckets  []int64
+	inFlight int64
+
+	srv *http.Server
+	ln  net.Listener
+}
+
+func newMetricsSink(listen string) (*metricsSink, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, err
+	}
+	s := &metricsSink{buckets: make([]int64, len(latencyBucketBoundsMs)), ln: ln}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	s.srv = &http.Server{Handler: mux}
+	go func() { _ = s.srv.Serve(ln) }()
+	return s, nil
+}
+
+func (s *metricsSink) OnRequestStart() {
+	atomic.AddInt64(&s.inFlight, 1)
+}
+
+func (s *metricsSink) OnRequestComplete() {
+	atomic.AddInt64(&s.inFlight, -1)
+}
+
+func (s *metricsSink) OnRecord(rr *ReportRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	if rr.error != "" || rr.code >= 500 {
+		s.errors++
+	}
+	ms := float64(rr.cost) / float64(time.Millisecond)
+	s.sum += ms
+	for i, bound := range latencyBucketBoundsMs {
+		if ms <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (s *metricsSink) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# TYPE plow_requests_total counter\nplow_requests_total %d\n", s.count)
+	fmt.Fprintf(&b, "# TYPE plow_errors_total counter\nplow_errors_total %d\n", s.errors)
+	fmt.Fprintf(&b, "# TYPE plow_in_flight gauge\nplow_in_flight %d\n", atomic.LoadInt64(&s.inFlight))
+	fmt.Fprintln(&b, "# TYPE plow_request_duration_milliseconds histogram")
+	for i, bound := range latencyBucketBoundsMs {
+		fmt.Fprintf(&b, "plow_request_duration_milliseconds_bucket{le=\"%g\"} %d\n", bound, s.buckets[i])
+	}
+	fmt.Fprintf(&b, "plow_request_duration_milliseconds_bucket{le=\"+Inf\"} %d\n", s.count)
+	fmt.Fprintf(&b, "plow_request_duration_milliseconds_sum %g\n", s.sum)
+	fmt.Fprintf(&b, "plow_request_duration_milliseconds_count %d\n", s.count)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write(b.Bytes())
+}
+
+func (s *metricsSink) Flush() error {
+	return s.srv.Close()
+}
+
+// ---- Prometheus remote_write sink ----
+
+// remoteWriteSink batches records into Prometheus remote_write protobuf
+// frames, following the stream-batching pattern vmagent uses so memory
+// stays bounded even at very high request rates.
+type remoteWriteSink struct {
+	url        string
+	httpClient *http.Client
+	batchSize  int
+
+	mu    sync.Mutex
+	batch []prompb.TimeSeries
+}
+
+func newRemoteWriteSink(url string) *remoteWriteSink {
+	return &remoteWriteSink{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		batchSize:  500,
+	}
+}
+
+func (s *remoteWriteSink) OnRequestStart()    {}
+func (s *remoteWriteSink) OnRequestComplete() {}
+
+func (s *remoteWriteSink) OnRecord(rr *ReportRecord) {
+	ts := prompb.TimeSeries{
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "plow_request_duration_milliseconds"},
+			{Name: "code", Value: strconv.Itoa(rr.code)},
+		},
+		Samples: []prompb.Sample{{
+			Value:     float64(rr.cost) / float64(time.Millisecond),
+			Timestamp: time.Now().UnixMilli(),
+		}},
+	}
+
+	s.mu.Lock()
+	s.batch = append(s.batch, ts)
+	full := len(s.batch) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		_ = s.Flush()
+	}
+}
+
+func (s *remoteWriteSink) Flush() error {
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: batch})
+	if err != nil {
+		return err
+	}
+	compressed := snappy.Encode(nil, data)
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(compressed))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote_write: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	url2 "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpproxy"
+)
+
+// TargetSpec is one entry of a weighted multi-target URL set, supplied
+// either via a repeated --target flag (see ParseTargetFlag) or a target
+// file (see LoadTargetsFile).
+type TargetSpec struct {
+	URL     string            `yaml:"url" json:"url"`
+	Method  string            `yaml:"method" json:"method"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Body    string            `yaml:"body" json:"body"`
+	Weight  float64           `yaml:"weight" json:"weight"`
+}
+
+// ParseTargetFlag parses a single "weight:method:url" --target flag value,
+// e.g. "80:GET:http://example.com/items/{id}". The repeated --target flag is
+// intentionally limited to weight/method/url, since headers and a body
+// don't have an unambiguous place in a single colon-delimited string; use a
+// target file (see LoadTargetsFile) for targets that need either.
+func ParseTargetFlag(spec string) (TargetSpec, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 {
+		return TargetSpec{}, fmt.Errorf("invalid --target %q, want weight:method:url", spec)
+	}
+	weight, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return TargetSpec{}, fmt.Errorf("invalid --target %q: %w", spec, err)
+	}
+	return TargetSpec{Weight: weight, Method: parts[1], URL: parts[2]}, nil
+}
+
+// LoadTargetsFile reads a weighted target list from a JSON file.
+func LoadTargetsFile(path string) ([]TargetSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var targets []TargetSpec
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("parse targets file %s: %w", path, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets file %s has no targets", path)
+	}
+	return targets, nil
+}
+
+// aliasSampler is Vose's alias method: after an O(n) build it draws a
+// weighted-random index in O(1), which matters here since Run calls it once
+// per request.
+type aliasSampler struct {
+	prob  []float64
+	alias []int
+}
+
+func newAliasSampler(weights []float64) (*aliasSampler, error) {
+	n := len(weights)
+	if n == 0 {
+		return nil, fmt.Errorf("no weights given")
+	}
+	var total float64
+	for _, w := range weights {
+		if w <= 0 {
+			return nil, fmt.Errorf("target weights must be positive, got %v", w)
+		}
+		total += w
+	}
+
+	scaled := make([]float64, n)
+	var small, large []int
+	for i, w := range weights {
+		scaled[i] = w * float64(n) / total
+		if scaled[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	s := &aliasSampler{prob: make([]float64, n), alias: make([]int, n)}
+	for len(small) > 0 && len(large) > 0 {
+		l := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		s.prob[l] = scaled[l]
+		s.alias[l] = g
+
+		scaled[g] = scaled[g] + scaled[l] - 1
+		if scaled[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, g := range large {
+		s.prob[g] = 1
+	}
+	for _, l := range small {
+		s.prob[l] = 1
+	}
+	return s, nil
+}
+
+func (s *aliasSampler) Next() int {
+	i := rand.Intn(len(s.prob))
+	if rand.Float64() < s.prob[i] {
+		return i
+	}
+	return s.alias[i]
+}
+
+// dataFileIterator round-robins lines of a CSV/JSONL data file across the
+// worker pool so each request substitutes a different {id} value.
+type dataFileIterator struct {
+	lines []string
+	next  int64
+}
+
+func loadDataFile(path string) (*dataFileIterator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	ext := strings.ToLower(filepath.Ext(path))
+	isCSV := ext == ".csv"
+	isJSONL := ext == ".jsonl"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		switch {
+		case isCSV:
+			line = strings.SplitN(line, ",", 2)[0]
+		case isJSONL:
+			// Pull the "id" field out of each JSON object rather than using
+			// the raw line, so {id} substitutes a single value and not the
+			// whole record; fall back to the raw line if it doesn't decode
+			// as an object with an "id" field.
+			var obj map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &obj); err == nil {
+				if v, ok := obj["id"]; ok {
+					line = stringify(v)
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("data file %s has no rows", path)
+	}
+	return &dataFileIterator{lines: lines}, nil
+}
+
+func (d *dataFileIterator) Next() string {
+	i := atomic.AddInt64(&d.next, 1) - 1
+	return d.lines[int(i%int64(len(d.lines)))]
+}
+
+// multiTarget is one resolved TargetSpec: a doer bound to its host:port
+// (shared across every target that resolves to the same addr, so unrelated
+// backends don't share a connection pool) plus the header/body to send.
+type multiTarget struct {
+	client      doer
+	header      *fasthttp.RequestHeader
+	uriTemplate string
+	bodyBytes   []byte
+	isTLS       bool
+	weight      float64
+}
+
+// buildMultiTargetClients resolves each TargetSpec to a multiTarget,
+// building one *fasthttp.HostClient per distinct host:port and sharing it
+// across every target on that backend.
+func buildMultiTargetClients(targets []TargetSpec, opt *ClientOpt, r *int64, w *int64) ([]*multiTarget, error) {
+	hostClients := make(map[string]*fasthttp.HostClient)
+	results := make([]*multiTarget, 0, len(targets))
+
+	for _, t := range targets {
+		u, err := url2.Parse(t.URL)
+		if err != nil {
+			return nil, err
+		}
+		addr := addMissingPort(u.Host, u.Scheme == "https")
+
+		hc, ok := hostClients[addr]
+		if !ok {
+			hc, err = buildHostClient(addr, u.Scheme == "https", opt, r, w)
+			if err != nil {
+				return nil, err
+			}
+			hostClients[addr] = hc
+		}
+
+		method := t.Method
+		if method == "" {
+			method = "GET"
+		}
+		var headers []string
+		for k, v := range t.Headers {
+			headers = append(headers, k+":"+v)
+		}
+		header, err := buildRequestHeader(&ClientOpt{
+			method:      method,
+			headers:     headers,
+			contentType: opt.contentType,
+			host:        opt.host,
+		}, u)
+		if err != nil {
+			return nil, err
+		}
+
+		// RequestURI escapes path braces, so restore them for the {id}
+		// substitution in runMultiTargetVUser.
+		uriTemplate := strings.Replace(u.RequestURI(), "%7Bid%7D", "{id}", -1)
+
+		results = append(results, &multiTarget{
+			client:      hc,
+			header:      header,
+			uriTemplate: uriTemplate,
+			bodyBytes:   []byte(t.Body),
+			isTLS:       u.Scheme == "https",
+			weight:      t.Weight,
+		})
+	}
+	return results, nil
+}
+
+// buildHostClient builds the *fasthttp.HostClient shared by every
+// multiTarget whose URL resolves to addr; it is the same construction
+// buildRequestClient uses for the single-target path.
+func buildHostClient(addr string, isTLS bool, opt *ClientOpt, r *int64, w *int64) (*fasthttp.HostClient, error) {
+	httpClient := &fasthttp.HostClient{
+		Addr:                          addr,
+		IsTLS:                         isTLS,
+		Name:                          "plow",
+		MaxConns:                      opt.maxConns,
+		ReadTimeout:                   opt.readTimeout,
+		WriteTimeout:                  opt.writeTimeout,
+		DisableHeaderNamesNormalizing: true,
+		StreamResponseBody:            opt.stream,
+	}
+	if opt.socks5Proxy != "" {
+		proxy := opt.socks5Proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "socks5://" + proxy
+		}
+		httpClient.Dial = fasthttpproxy.FasthttpSocksDialer(proxy)
+	} else {
+		httpClient.Dial = fasthttpproxy.FasthttpProxyHTTPDialerTimeout(opt.dialTimeout)
+	}
+	httpClient.Dial = ThroughputInterceptorDial(httpClient.Dial, r, w)
+
+	tlsConfig, err := buildTLSConfig(opt)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.TLSConfig = tlsConfig
+
+	return httpClient, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "strings"
+
+// renderTemplate substitutes ${name} placeholders in s with values captured
+// earlier in the scenario run. Unknown placeholders are left untouched so
+// malformed variable references are easy to spot in the recorded request.
+func renderTemplate(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "${") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			b.WriteString(s)
+			break
+		}
+		end += start
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		if v, ok := vars[name]; ok {
+			b.WriteString(v)
+		} else {
+			b.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	return b.String()
+}
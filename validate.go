@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxValidatedBodyBytes bounds how much of a streamed response body the
+// regex/content-type validators buffer for inspection; past that point
+// bytes are still counted and discarded but no longer retained, so
+// validating a multi-gigabyte stream stays close to allocation-free.
+const maxValidatedBodyBytes = 1 << 20 // 1 MiB
+
+// validationError marks a validator failure so DoRequest can surface it as
+// "validation: ..." alongside transport errors in the error breakdown.
+type validationError struct{ msg string }
+
+func (e *validationError) Error() string { return "validation: " + e.msg }
+
+// Validator inspects a streamed response. Header runs once status and
+// headers are available; Body runs once the (possibly truncated) body has
+// been read, with the true total size regardless of truncation.
+type Validator interface {
+	Header(statusCode int, header *fasthttp.ResponseHeader) error
+	Body(prefix []byte, size int64) error
+}
+
+// buildValidators turns the --expect-* ClientOpt fields into Validators; it
+// returns nil when none were requested.
+func buildValidators(opt *ClientOpt) ([]Validator, error) {
+	if !opt.stream {
+		switch {
+		case opt.expectBodyRegex != "":
+			return nil, fmt.Errorf("--expect-body-regex requires --stream")
+		case opt.expectBodySize != "":
+			return nil, fmt.Errorf("--expect-body-size requires --stream")
+		case opt.expectContentType != "":
+			return nil, fmt.Errorf("--expect-content-type requires --stream")
+		}
+	}
+
+	var validators []Validator
+
+	if opt.expectStatus != "" {
+		v, err := newStatusValidator(opt.expectStatus)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, v)
+	}
+	for _, spec := range opt.expectHeaders {
+		v, err := newHeaderValidator(spec)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, v)
+	}
+	if opt.expectBodyRegex != "" {
+		v, err := newBodyRegexValidator(opt.expectBodyRegex)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, v)
+	}
+	if opt.expectBodySize != "" {
+		v, err := newBodySizeValidator(opt.expectBodySize)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, v)
+	}
+	if opt.expectContentType != "" {
+		validators = append(validators, &contentTypeValidator{want: opt.expectContentType})
+	}
+
+	return validators, nil
+}
+
+// validateHeader runs every validator's Header check.
+func validateHeader(validators []Validator, resp *fasthttp.Response) error {
+	for _, v := range validators {
+		if err := v.Header(resp.StatusCode(), &resp.Header); err != nil {
+			return &validationError{msg: err.Error()}
+		}
+	}
+	return nil
+}
+
+// validateBody runs every validator's Body check against the (possibly
+// truncated) buffered prefix and the true total size.
+func validateBody(validators []Validator, prefix []byte, size int64) error {
+	for _, v := range validators {
+		if err := v.Body(prefix, size); err != nil {
+			return &validationError{msg: err.Error()}
+		}
+	}
+	return nil
+}
+
+// streamBody drains resp's body stream incrementally into writeTo, counting
+// bytes without retaining them beyond the first maxValidatedBodyBytes, then
+// runs the Body half of validators against that prefix.
+func streamBody(resp *fasthttp.Response, writeTo io.Writer, validators []Validator) error {
+	bs := resp.BodyStream()
+	if bs == nil {
+		// No stream was set up (e.g. an empty body); fall back to the
+		// already-buffered body so validators still run consistently.
+		body := resp.Body()
+		if writeTo != io.Discard {
+			if _, err := writeTo.Write(body); err != nil {
+				return err
+			}
+		}
+		return validateBody(validators, body, int64(len(body)))
+	}
+
+	var prefix bytes.Buffer
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := bs.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			if prefix.Len() < maxValidatedBodyBytes {
+				remain := maxValidatedBodyBytes - prefix.Len()
+				if remain > n {
+					remain = n
+				}
+				prefix.Write(buf[:remain])
+			}
+			if writeTo != io.Discard {
+				if _, werr := writeTo.Write(buf[:n]); werr != nil {
+					return werr
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return validateBody(validators, prefix.Bytes(), total)
+}
+
+// drainBodyStream discards resp's body without validation, used when a
+// header validator already failed but the connection still needs draining
+// so it can be reused.
+func drainBodyStream(resp *fasthttp.Response, writeTo io.Writer, streaming bool) error {
+	if !streaming {
+		return resp.BodyWriteTo(writeTo)
+	}
+	bs := resp.BodyStream()
+	if bs == nil {
+		return nil
+	}
+	_, err := io.Copy(writeTo, bs)
+	return err
+}
+
+// statusValidator matches either an exact code ("404") or a class pattern
+// ("2xx").
+type statusValidator struct {
+	exact int
+	class int // hundreds digit when pattern is "Nxx"; -1 when exact is used
+}
+
+func newStatusValidator(pattern string) (*statusValidator, error) {
+	if len(pattern) == 3 && strings.HasSuffix(pattern, "xx") {
+		class, err := strconv.Atoi(pattern[:1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expect-status %q", pattern)
+		}
+		return &statusValidator{exact: -1, class: class}, nil
+	}
+	code, err := strconv.Atoi(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --expect-status %q", pattern)
+	}
+	return &statusValidator{exact: code, class: -1}, nil
+}
+
+func (v *statusValidator) Header(statusCode int, _ *fasthttp.ResponseHeader) error {
+	if v.class >= 0 {
+		if statusCode/100 != v.class {
+			return fmt.Errorf("status %d not in %dxx", statusCode, v.class)
+		}
+		return nil
+	}
+	if statusCode != v.exact {
+		return fmt.Errorf("status %d != %d", statusCode, v.exact)
+	}
+	return nil
+}
+
+func (v *statusValidator) Body([]byte, int64) error { return nil }
+
+// headerValidator checks a "Key:Regex" spec against a response header.
+type headerValidator struct {
+	key string
+	re  *regexp.Regexp
+}
+
+func newHeaderValidator(spec string) (*headerValidator, error) {
+	n := strings.SplitN(spec, ":", 2)
+	if len(n) != 2 {
+		return nil, fmt.Errorf("invalid --expect-header %q, want Key:Regex", spec)
+	}
+	re, err := regexp.Compile(n[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid --expect-header regex %q: %w", spec, err)
+	}
+	return &headerValidator{key: n[0], re: re}, nil
+}
+
+func (v *headerValidator) Header(_ int, header *fasthttp.ResponseHeader) error {
+	value := header.Peek(v.key)
+	if !v.re.Match(value) {
+		return fmt.Errorf("header %s=%q does not match %s", v.key, value, v.re.String())
+	}
+	return nil
+}
+
+func (v *headerValidator) Body([]byte, int64) error { return nil }
+
+// bodyRegexValidator matches against up to maxValidatedBodyBytes of body.
+type bodyRegexValidator struct {
+	re *regexp.Regexp
+}
+
+func newBodyRegexValidator(pattern string) (*bodyRegexValidator, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --expect-body-regex %q: %w", pattern, err)
+	}
+	return &bodyRegexValidator{re: re}, nil
+}
+
+func (v *bodyRegexValidator) Header(int, *fasthttp.ResponseHeader) error { return nil }
+
+func (v *bodyRegexValidator) Body(prefix []byte, _ int64) error {
+	if !v.re.Match(prefix) {
+		return fmt.Errorf("body-regex no match")
+	}
+	return nil
+}
+
+// bodySizeValidator parses "N" or "N±T" (e.g. "1024±10") and checks the
+// true total size, not just the buffered prefix.
+type bodySizeValidator struct {
+	want, tolerance int64
+}
+
+func newBodySizeValidator(spec string) (*bodySizeValidator, error) {
+	want, tolerance := spec, "0"
+	if i := strings.Index(spec, "±"); i >= 0 {
+		want, tolerance = spec[:i], spec[i+len("±"):]
+	}
+	w, err := strconv.ParseInt(strings.TrimSpace(want), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --expect-body-size %q: %w", spec, err)
+	}
+	t, err := strconv.ParseInt(strings.TrimSpace(tolerance), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --expect-body-size %q: %w", spec, err)
+	}
+	return &bodySizeValidator{want: w, tolerance: t}, nil
+}
+
+func (v *bodySizeValidator) Header(int, *fasthttp.ResponseHeader) error { return nil }
+
+func (v *bodySizeValidator) Body(_ []byte, size int64) error {
+	diff := size - v.want
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > v.tolerance {
+		return fmt.Errorf("body-size %d outside %d±%d", size, v.want, v.tolerance)
+	}
+	return nil
+}
+
+// contentTypeValidator checks the Content-Type header and, for
+// "application/json", that the buffered prefix is well-formed JSON; that
+// check is best-effort when the body was truncated at maxValidatedBodyBytes.
+type contentTypeValidator struct {
+	want string
+}
+
+func (v *contentTypeValidator) Header(_ int, header *fasthttp.ResponseHeader) error {
+	got := string(header.ContentType())
+	if i := strings.IndexByte(got, ';'); i >= 0 {
+		got = got[:i]
+	}
+	if strings.TrimSpace(got) != v.want {
+		return fmt.Errorf("content-type %q != %q", got, v.want)
+	}
+	return nil
+}
+
+func (v *contentTypeValidator) Body(prefix []byte, size int64) error {
+	if v.want != "application/json" {
+		return nil
+	}
+	if size > int64(len(prefix)) {
+		// Truncated: only check that the buffered prefix looks like the
+		// start of a JSON value, since a full parse isn't possible.
+		trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+		if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+			return fmt.Errorf("body does not start like JSON")
+		}
+		return nil
+	}
+	if !json.Valid(prefix) {
+		return fmt.Errorf("body is not well-formed JSON")
+	}
+	return nil
+}